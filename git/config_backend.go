@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"os"
+
+	"github.com/git-lfs/git-lfs/v3/tr"
+)
+
+// ConfigBackend abstracts the operations needed to read and write Git
+// configuration at the local, worktree, system, and global scopes.
+// Configuration (which shells out to the git executable) is the default
+// implementation; NewFileConfigBackend provides one that reads and writes
+// the underlying config files directly, without requiring a git binary on
+// PATH.
+type ConfigBackend interface {
+	FindLocal(key string) string
+	FindWorktree(key string) string
+	FindSystem(key string) string
+	FindGlobal(key string) string
+
+	SetLocal(key, value string) (string, error)
+	SetWorktree(key, value string) (string, error)
+	SetSystem(key, value string) (string, error)
+	SetGlobal(key, value string) (string, error)
+
+	UnsetLocalSection(key string) (string, error)
+	UnsetWorktreeSection(key string) (string, error)
+	UnsetSystemSection(key string) (string, error)
+	UnsetGlobalSection(key string) (string, error)
+}
+
+var _ ConfigBackend = (*Configuration)(nil)
+
+// ConfigBackendKind names a ConfigBackend implementation that can be
+// selected at runtime via FilterOptions or the LFS_CONFIG_BACKEND
+// environment variable.
+type ConfigBackendKind string
+
+const (
+	// ExecConfigBackend shells out to the git executable for every
+	// read and write. It is the default, and requires git to be on PATH.
+	ExecConfigBackend ConfigBackendKind = "exec"
+
+	// GoGitConfigBackendKind reads and writes the repository's
+	// .git/config, the user's ~/.gitconfig, and the system gitconfig
+	// directly, without invoking the git executable. The name predates
+	// this package dropping its dependency on go-git's config encoder
+	// (which could not round-trip comments; see FileConfigBackend), but
+	// is kept as the stable value of LFS_CONFIG_BACKEND.
+	GoGitConfigBackendKind ConfigBackendKind = "go-git"
+)
+
+// EnvConfigBackend is the environment variable consulted by
+// ResolveConfigBackendKind when nothing more specific has been set.
+const EnvConfigBackend = "LFS_CONFIG_BACKEND"
+
+// ResolveConfigBackendKind determines which ConfigBackend implementation to
+// construct. An explicit kind, such as one set on FilterOptions, always
+// wins; otherwise the LFS_CONFIG_BACKEND environment variable is consulted,
+// and ExecConfigBackend is used if neither is set.
+func ResolveConfigBackendKind(explicit ConfigBackendKind) ConfigBackendKind {
+	if explicit != "" {
+		return explicit
+	}
+	if v := os.Getenv(EnvConfigBackend); v != "" {
+		return ConfigBackendKind(v)
+	}
+	return ExecConfigBackend
+}
+
+// NewConfigBackend constructs the ConfigBackend named by kind. cfg is used
+// directly when kind is ExecConfigBackend, and is used to locate the
+// repository when kind is GoGitConfigBackendKind.
+func NewConfigBackend(kind ConfigBackendKind, cfg *Configuration) (ConfigBackend, error) {
+	switch kind {
+	case "", ExecConfigBackend:
+		return cfg, nil
+	case GoGitConfigBackendKind:
+		return NewFileConfigBackend(cfg)
+	default:
+		return nil, errors.New(tr.Tr.Get("unknown config backend %q", string(kind)))
+	}
+}
@@ -0,0 +1,650 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// FileConfigBackend is a ConfigBackend that reads and writes Git
+// configuration files directly, instead of shelling out to the git
+// executable. This lets `git lfs install`, `git lfs uninstall`, and the
+// filter attribute plumbing run in environments where no git binary is on
+// PATH, such as containerized CI images or language-embedded runtimes.
+//
+// It was originally built on go-git's config.Config parser, but that parser
+// discards comments on every write, which a comment-preserving backend
+// can't tolerate; this type now patches config files' text directly instead
+// and no longer depends on go-git. (See GoGitConfigBackendKind, whose name
+// predates this change and is kept for LFS_CONFIG_BACKEND compatibility.)
+//
+// Local scope reads and writes the repository's shared .git/config (the
+// "commondir", in a checkout with linked worktrees); in a linked worktree,
+// worktree scope reads and writes config.worktree in that worktree's own git
+// directory, but only once extensions.worktreeConfig is enabled - otherwise,
+// and always in the main checkout, "worktree" scope falls back to local
+// scope, the same way a plain repository without linked worktrees behaves. Global and
+// system scopes read and write the user's and machine's gitconfig files.
+//
+// Writes are applied as a line-oriented patch of the existing file rather
+// than a full parse/re-encode, so sections and keys this backend doesn't
+// touch - including comments, include directives, and section order -
+// survive a round trip unchanged.
+type FileConfigBackend struct {
+	// startDir is where repository discovery begins; it is only resolved
+	// into gitDir/worktreeDir lazily, the first time Local or Worktree
+	// scope is actually used (see repoDirs), so constructing a backend -
+	// and using its Global/System scopes - works outside any repository.
+	startDir string
+
+	mu          sync.Mutex
+	resolved    bool
+	gitDir      string
+	worktreeDir string
+	resolveErr  error
+
+	globalPath string
+	// systemPath is where system scope is read from and written to, or
+	// empty if GIT_CONFIG_NOSYSTEM disables system config entirely.
+	systemPath string
+}
+
+// NewFileConfigBackend constructs a FileConfigBackend rooted at cfg's
+// working directory (honoring the same "-C <dir>" / subdirectory invocation
+// the exec-based backend already does), falling back to the process's
+// current directory if cfg doesn't have one resolved. Repository discovery
+// itself - which can fail when run outside a repository - is deferred until
+// Local or Worktree scope is actually used; System and Global scopes work
+// immediately, as does `git lfs install --global` with no repository
+// present at all.
+func NewFileConfigBackend(cfg *Configuration) (*FileConfigBackend, error) {
+	start, err := startingDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = ""
+	}
+
+	return &FileConfigBackend{
+		startDir:   start,
+		globalPath: filepath.Join(home, ".gitconfig"),
+		systemPath: systemConfigPath(),
+	}, nil
+}
+
+// startingDir returns the directory repository discovery should begin from:
+// cfg's own resolved working directory when it has one, or the process's
+// current directory otherwise.
+func startingDir(cfg *Configuration) (string, error) {
+	if cfg != nil {
+		if wd := cfg.LocalWorkingDir(); wd != "" {
+			return wd, nil
+		}
+	}
+	return os.Getwd()
+}
+
+func (b *FileConfigBackend) FindLocal(key string) string {
+	path, err := b.localPath()
+	if err != nil {
+		return ""
+	}
+	return b.find(path, key)
+}
+
+func (b *FileConfigBackend) FindWorktree(key string) string {
+	path, err := b.worktreeConfigPath()
+	if err != nil {
+		return ""
+	}
+	return b.find(path, key)
+}
+
+func (b *FileConfigBackend) FindSystem(key string) string { return b.find(b.systemPath, key) }
+func (b *FileConfigBackend) FindGlobal(key string) string { return b.find(b.globalPath, key) }
+
+func (b *FileConfigBackend) SetLocal(key, value string) (string, error) {
+	path, err := b.localPath()
+	if err != nil {
+		return "", err
+	}
+	return b.set(path, key, value)
+}
+
+func (b *FileConfigBackend) SetWorktree(key, value string) (string, error) {
+	path, err := b.worktreeConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return b.set(path, key, value)
+}
+
+func (b *FileConfigBackend) SetSystem(key, value string) (string, error) {
+	return b.set(b.systemPath, key, value)
+}
+
+func (b *FileConfigBackend) SetGlobal(key, value string) (string, error) {
+	return b.set(b.globalPath, key, value)
+}
+
+func (b *FileConfigBackend) UnsetLocalSection(key string) (string, error) {
+	path, err := b.localPath()
+	if err != nil {
+		return "", err
+	}
+	return b.unsetSection(path, key)
+}
+
+func (b *FileConfigBackend) UnsetWorktreeSection(key string) (string, error) {
+	path, err := b.worktreeConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return b.unsetSection(path, key)
+}
+
+func (b *FileConfigBackend) UnsetSystemSection(key string) (string, error) {
+	return b.unsetSection(b.systemPath, key)
+}
+
+func (b *FileConfigBackend) UnsetGlobalSection(key string) (string, error) {
+	return b.unsetSection(b.globalPath, key)
+}
+
+// repoDirs lazily discovers and caches this checkout's own git directory
+// and its shared (common) git directory, only failing - and only doing any
+// work at all - the first time Local or Worktree scope is actually used.
+func (b *FileConfigBackend) repoDirs() (gitDir, worktreeDir string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.resolved {
+		b.worktreeDir, b.resolveErr = discoverGitDir(b.startDir)
+		if b.resolveErr == nil {
+			b.gitDir, b.resolveErr = resolveCommonDir(b.worktreeDir)
+		}
+		b.resolved = true
+	}
+	return b.gitDir, b.worktreeDir, b.resolveErr
+}
+
+func (b *FileConfigBackend) localPath() (string, error) {
+	gitDir, _, err := b.repoDirs()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "config"), nil
+}
+
+// worktreeConfigPath returns the file that holds this checkout's
+// worktree-scoped config. Real git only honors a per-worktree
+// config.worktree file once extensions.worktreeConfig is set to true in the
+// shared local config; until then - and always in the main checkout, whose
+// own git directory is the shared one, whether or not linked worktrees
+// exist - "worktree" scope is simply an alias for "local" scope.
+// This backend mirrors that fallback instead of always writing the two
+// scopes to the same file regardless of the extension.
+func (b *FileConfigBackend) worktreeConfigPath() (string, error) {
+	gitDir, worktreeDir, err := b.repoDirs()
+	if err != nil {
+		return "", err
+	}
+	localPath := filepath.Join(gitDir, "config")
+	if worktreeDir == gitDir {
+		return localPath, nil
+	}
+
+	cfg, err := b.load(localPath)
+	if err != nil || !strings.EqualFold(lookupOption(cfg, "extensions.worktreeconfig"), "true") {
+		return localPath, nil
+	}
+	return filepath.Join(worktreeDir, "config.worktree"), nil
+}
+
+func (b *FileConfigBackend) find(path, key string) string {
+	if path == "" {
+		return ""
+	}
+	cfg, err := b.load(path)
+	if err != nil {
+		return ""
+	}
+	return lookupOption(cfg, key)
+}
+
+func (b *FileConfigBackend) set(path, key, value string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("git: config scope is disabled")
+	}
+	section, subsection, option := splitKey(key)
+
+	raw, err := readConfigFile(path)
+	if err != nil {
+		return "", err
+	}
+	return value, writeConfigFile(path, setConfigValue(raw, section, subsection, option, value))
+}
+
+func (b *FileConfigBackend) unsetSection(path, key string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	section, subsection := splitSectionKey(key)
+
+	raw, err := readConfigFile(path)
+	if err != nil {
+		return "", err
+	}
+	return "", writeConfigFile(path, removeConfigSection(raw, section, subsection))
+}
+
+// load parses the config file at path for lookups, treating a missing file
+// as an empty configuration so that Find* calls against an uninitialized
+// scope simply come back empty, matching the exec backend's behavior.
+func (b *FileConfigBackend) load(path string) (*parsedConfig, error) {
+	raw, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(raw), nil
+}
+
+// readConfigFile reads path, treating a missing file as empty content so
+// that writing to an uninitialized scope starts from a blank file instead
+// of failing.
+func readConfigFile(path string) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func writeConfigFile(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o644)
+}
+
+// discoverGitDir walks upward from startDir looking for a ".git" entry, the
+// way git itself resolves which repository a working directory belongs to,
+// and returns this checkout's own git directory: ".git" itself when it's a
+// directory, or the directory it points to via a "gitdir: <path>" line when
+// it's a file, as left behind by submodules and linked worktrees.
+func discoverGitDir(startDir string) (string, error) {
+	dir := startDir
+	for {
+		candidate := filepath.Join(dir, ".git")
+		info, err := os.Stat(candidate)
+		switch {
+		case err == nil && info.IsDir():
+			return candidate, nil
+		case err == nil:
+			return readGitFile(candidate)
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("git: no .git directory found above %q", startDir)
+		}
+		dir = parent
+	}
+}
+
+// readGitFile follows the "gitdir: <path>" pointer left in a ".git" file,
+// returning the directory it points at.
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	line := strings.TrimSpace(string(data))
+	const prefix = "gitdir: "
+	if !strings.HasPrefix(line, prefix) {
+		return "", fmt.Errorf("git: malformed .git file %q", path)
+	}
+
+	target := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// resolveCommonDir returns the shared git directory for worktreeDir: the
+// directory a "commondir" file inside it points to, for a linked worktree
+// or a submodule, or worktreeDir itself when no such file exists (a plain
+// repository with no linked worktrees).
+func resolveCommonDir(worktreeDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(worktreeDir, "commondir"))
+	if os.IsNotExist(err) {
+		return worktreeDir, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(worktreeDir, common)
+	}
+	return filepath.Clean(common), nil
+}
+
+// systemConfigPath resolves the system-scope config file the way git
+// itself does: GIT_CONFIG_NOSYSTEM disables it outright (returning ""),
+// GIT_CONFIG_SYSTEM overrides the path, and otherwise it falls back to the
+// platform's conventional location rather than a hardcoded Linux path.
+func systemConfigPath() string {
+	if os.Getenv("GIT_CONFIG_NOSYSTEM") != "" {
+		return ""
+	}
+	if p := os.Getenv("GIT_CONFIG_SYSTEM"); p != "" {
+		return p
+	}
+	return defaultSystemConfigPath()
+}
+
+func defaultSystemConfigPath() string {
+	switch runtime.GOOS {
+	case "windows":
+		root := os.Getenv("PROGRAMDATA")
+		if root == "" {
+			root = `C:\ProgramData`
+		}
+		return filepath.Join(root, "Git", "config")
+	case "darwin":
+		// Matches the location used by Git for Mac and Homebrew's
+		// git formula; a git binary built with a different --prefix
+		// would use a different path, but GIT_CONFIG_SYSTEM is the
+		// escape hatch for that.
+		return "/usr/local/etc/gitconfig"
+	default:
+		return "/etc/gitconfig"
+	}
+}
+
+// splitKey translates a dotted LFS config key, such as "filter.lfs.clean",
+// into the section/subsection/option addressing a config file uses. The
+// first component is always the section and the last is always the option;
+// anything in between (which may itself contain dots) is the subsection.
+func splitKey(key string) (section, subsection, option string) {
+	first := strings.IndexByte(key, '.')
+	if first < 0 {
+		return key, "", ""
+	}
+	section = key[:first]
+	rest := key[first+1:]
+
+	last := strings.LastIndexByte(rest, '.')
+	if last < 0 {
+		return section, "", rest
+	}
+	return section, rest[:last], rest[last+1:]
+}
+
+// splitSectionKey translates a dotted section key, such as "filter.lfs" (as
+// passed to Unset*Section, addressing an entire [section "subsection"]
+// block rather than one option within it), into its section and optional
+// subsection.
+func splitSectionKey(key string) (section, subsection string) {
+	idx := strings.IndexByte(key, '.')
+	if idx < 0 {
+		return key, ""
+	}
+	return key[:idx], key[idx+1:]
+}
+
+// parsedConfig is a read-only view of a config file's sections, built by
+// parseConfig purely for Find* lookups; writes go through the line-based
+// patch functions below instead, so they can preserve comments and
+// formatting that a parse/re-encode round trip would otherwise drop.
+type parsedConfig struct {
+	// options maps section, subsection and option, joined by NUL bytes
+	// as optionKey builds them (all lowercased except subsection, which
+	// git treats as case-sensitive), to the last value
+	// assigned to it, matching git's "last one wins" semantics for
+	// repeated keys.
+	options map[string]string
+}
+
+func optionKey(section, subsection, option string) string {
+	return strings.ToLower(section) + "\x00" + subsection + "\x00" + strings.ToLower(option)
+}
+
+func parseConfig(src []byte) *parsedConfig {
+	pc := &parsedConfig{options: make(map[string]string)}
+
+	section, subsection := "", ""
+	for _, line := range splitLines(src) {
+		if name, sub, ok := parseSectionHeader(line); ok {
+			section, subsection = name, sub
+			continue
+		}
+		if section == "" {
+			continue
+		}
+		if option, value, ok := parseOptionLine(line); ok {
+			pc.options[optionKey(section, subsection, option)] = value
+		}
+	}
+
+	return pc
+}
+
+func lookupOption(cfg *parsedConfig, key string) string {
+	section, subsection, option := splitKey(key)
+	return cfg.options[optionKey(section, subsection, option)]
+}
+
+var (
+	sectionHeaderPattern = regexp.MustCompile(`^\s*\[\s*([A-Za-z0-9_-]+)(?:\s+"((?:[^"\\]|\\.)*)")?\s*\]`)
+	optionLinePattern    = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9-]*)\s*(?:=(.*))?$`)
+)
+
+// parseSectionHeader reports whether line is a section header, such as
+// "[section]" or "[section \"subsection\"]", and if so returns its name and
+// (possibly empty) subsection.
+func parseSectionHeader(line string) (section, subsection string, ok bool) {
+	m := sectionHeaderPattern.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], unescapeSubsection(m[2]), true
+}
+
+// parseOptionLine reports whether line (which must not be a section header)
+// assigns a value to an option, ignoring comment and blank lines, and if so
+// returns the option name and its (trimmed, unquoted) value. A trailing,
+// unquoted "# ..." or "; ..." comment on the same line is stripped before
+// the key and value are parsed out of it.
+func parseOptionLine(line string) (option, value string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+		return "", "", false
+	}
+
+	m := optionLinePattern.FindStringSubmatch(stripInlineComment(line))
+	if m == nil {
+		return "", "", false
+	}
+	if m[2] == "" && !strings.Contains(m[0], "=") {
+		// A bare "key" line is shorthand for "key = true".
+		return m[1], "true", true
+	}
+	return m[1], unquoteValue(strings.TrimSpace(m[2])), true
+}
+
+// stripInlineComment returns s with its first unquoted, unescaped '#' or
+// ';' and everything after it removed, so that "key = value # comment"
+// yields "key = value " rather than treating the comment as part of the
+// value.
+func stripInlineComment(s string) string {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++ // skip the escaped character, whether quoted or not
+		case '"':
+			inQuotes = !inQuotes
+		case '#', ';':
+			if !inQuotes {
+				return s[:i]
+			}
+		}
+	}
+	return s
+}
+
+func unescapeSubsection(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func unquoteValue(v string) string {
+	if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+		return unescapeSubsection(v[1 : len(v)-1])
+	}
+	return v
+}
+
+// setConfigValue returns src with section.subsection.option set to value,
+// preserving every line it doesn't need to touch - comments, blank lines,
+// include directives, and the relative order of every other section - by
+// patching the existing text instead of decoding and re-encoding it.
+func setConfigValue(src []byte, section, subsection, option, value string) []byte {
+	lines := splitLines(src)
+	newLine := formatOptionLine(option, value)
+
+	start, end, found := findSectionBlock(lines, section, subsection)
+	if !found {
+		out := append([]string{}, lines...)
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, formatSectionHeader(section, subsection), newLine)
+		return joinLines(out)
+	}
+
+	if idx, ok := findOptionLine(lines[start+1:end], option); ok {
+		lines[start+1+idx] = newLine
+		return joinLines(lines)
+	}
+
+	out := make([]string, 0, len(lines)+1)
+	out = append(out, lines[:end]...)
+	out = append(out, newLine)
+	out = append(out, lines[end:]...)
+	return joinLines(out)
+}
+
+// removeConfigSection returns src with the entire [section] or
+// [section "subsection"] block (header and body) removed, leaving
+// everything else untouched.
+func removeConfigSection(src []byte, section, subsection string) []byte {
+	lines := splitLines(src)
+	start, end, found := findSectionBlock(lines, section, subsection)
+	if !found {
+		return src
+	}
+
+	out := make([]string, 0, len(lines))
+	out = append(out, lines[:start]...)
+	out = append(out, lines[end:]...)
+	return joinLines(out)
+}
+
+// findSectionBlock locates the [section] or [section "subsection"] header
+// matching section (case-insensitively) and subsection (case-sensitively,
+// as git treats subsection names), returning the index of its header line
+// and the index just past its last body line (i.e. the next section header,
+// or len(lines)).
+func findSectionBlock(lines []string, section, subsection string) (start, end int, found bool) {
+	for i, line := range lines {
+		name, sub, ok := parseSectionHeader(line)
+		if !ok || !strings.EqualFold(name, section) || sub != subsection {
+			continue
+		}
+
+		end = len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if _, _, ok := parseSectionHeader(lines[j]); ok {
+				end = j
+				break
+			}
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// findOptionLine scans a section's body lines for one assigning option
+// (case-insensitively), returning its index relative to the start of body.
+func findOptionLine(body []string, option string) (int, bool) {
+	for i, line := range body {
+		name, _, ok := parseOptionLine(line)
+		if ok && strings.EqualFold(name, option) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func formatSectionHeader(section, subsection string) string {
+	if subsection == "" {
+		return "[" + section + "]"
+	}
+	return fmt.Sprintf("[%s %q]", section, subsection)
+}
+
+func formatOptionLine(option, value string) string {
+	return "\t" + option + " = " + quoteValueIfNeeded(value)
+}
+
+// quoteValueIfNeeded quotes value when writing it unquoted would change its
+// meaning or lose information: leading/trailing whitespace, a comment
+// character, or an empty string.
+func quoteValueIfNeeded(value string) string {
+	needsQuoting := value == "" ||
+		value != strings.TrimSpace(value) ||
+		strings.ContainsAny(value, "#;")
+	if !needsQuoting {
+		return value
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+func splitLines(src []byte) []string {
+	if len(src) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+}
+
+func joinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
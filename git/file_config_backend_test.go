@@ -0,0 +1,233 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitKey(t *testing.T) {
+	cases := []struct {
+		key                         string
+		section, subsection, option string
+	}{
+		{"core.bare", "core", "", "bare"},
+		{"filter.lfs.clean", "filter", "lfs", "clean"},
+		{"remote.origin.fetch.refspec", "remote", "origin.fetch", "refspec"},
+		{"core", "core", "", ""},
+	}
+
+	for _, c := range cases {
+		section, subsection, option := splitKey(c.key)
+		if section != c.section || subsection != c.subsection || option != c.option {
+			t.Errorf("splitKey(%q) = (%q, %q, %q), want (%q, %q, %q)",
+				c.key, section, subsection, option, c.section, c.subsection, c.option)
+		}
+	}
+}
+
+func TestSplitSectionKey(t *testing.T) {
+	section, subsection := splitSectionKey("filter.lfs")
+	if section != "filter" || subsection != "lfs" {
+		t.Fatalf("got (%q, %q), want (%q, %q)", section, subsection, "filter", "lfs")
+	}
+
+	section, subsection = splitSectionKey("core")
+	if section != "core" || subsection != "" {
+		t.Fatalf("got (%q, %q), want (%q, %q)", section, subsection, "core", "")
+	}
+}
+
+func TestSetConfigValuePreservesCommentsAndFormatting(t *testing.T) {
+	src := []byte("# a top-level comment\n[core]\n\tbare = false\n\n[filter \"lfs\"]\n\t; about to change\n\tclean = git-lfs clean -- %f\n")
+
+	out := setConfigValue(src, "filter", "lfs", "clean", "git-lfs clean -- %f -v")
+
+	want := "# a top-level comment\n[core]\n\tbare = false\n\n[filter \"lfs\"]\n\t; about to change\n\tclean = git-lfs clean -- %f -v\n"
+	if string(out) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestSetConfigValueAppendsNewSection(t *testing.T) {
+	src := []byte("[core]\n\tbare = false\n")
+
+	out := setConfigValue(src, "filter", "lfs", "clean", "git-lfs clean -- %f")
+
+	want := "[core]\n\tbare = false\n\n[filter \"lfs\"]\n\tclean = git-lfs clean -- %f\n"
+	if string(out) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRemoveConfigSectionPreservesSurroundingComments(t *testing.T) {
+	src := []byte("# keep me\n[filter \"lfs\"]\n\tclean = git-lfs clean -- %f\n\tsmudge = git-lfs smudge -- %f\n[core]\n\tbare = false\n")
+
+	out := removeConfigSection(src, "filter", "lfs")
+
+	want := "# keep me\n[core]\n\tbare = false\n"
+	if string(out) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestParseConfigLastValueWins(t *testing.T) {
+	src := []byte("[core]\n\tbare = false\n\tbare = true\n")
+	cfg := parseConfig(src)
+
+	if got := lookupOption(cfg, "core.bare"); got != "true" {
+		t.Fatalf("got %q, want %q", got, "true")
+	}
+}
+
+func TestParseConfigBareKeyIsTrue(t *testing.T) {
+	src := []byte("[core]\n\tbare\n")
+	cfg := parseConfig(src)
+
+	if got := lookupOption(cfg, "core.bare"); got != "true" {
+		t.Fatalf("got %q, want %q", got, "true")
+	}
+}
+
+func TestDiscoverGitDirFromSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	if err := os.Mkdir(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := discoverGitDir(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != gitDir {
+		t.Fatalf("got %q, want %q", got, gitDir)
+	}
+}
+
+func TestDiscoverGitDirFollowsSubmoduleGitFile(t *testing.T) {
+	root := t.TempDir()
+	realGitDir := filepath.Join(root, "main-git", "modules", "sub")
+	if err := os.MkdirAll(realGitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	subRepo := filepath.Join(root, "sub")
+	if err := os.Mkdir(subRepo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(subRepo, ".git"), []byte("gitdir: "+realGitDir+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := discoverGitDir(subRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != realGitDir {
+		t.Fatalf("got %q, want %q", got, realGitDir)
+	}
+}
+
+func TestResolveCommonDirFollowsCommondirFile(t *testing.T) {
+	root := t.TempDir()
+	mainGitDir := filepath.Join(root, ".git")
+	worktreeDir := filepath.Join(mainGitDir, "worktrees", "feature")
+	if err := os.MkdirAll(worktreeDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(worktreeDir, "commondir"), []byte("../..\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resolveCommonDir(worktreeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != mainGitDir {
+		t.Fatalf("got %q, want %q", got, mainGitDir)
+	}
+}
+
+func TestResolveCommonDirWithoutLinkedWorktree(t *testing.T) {
+	root := t.TempDir()
+	gitDir := filepath.Join(root, ".git")
+	if err := os.Mkdir(gitDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := resolveCommonDir(gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != gitDir {
+		t.Fatalf("got %q, want %q", got, gitDir)
+	}
+}
+
+func TestSystemConfigPathHonorsNoSystem(t *testing.T) {
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	if got := systemConfigPath(); got != "" {
+		t.Fatalf("got %q, want empty with GIT_CONFIG_NOSYSTEM set", got)
+	}
+}
+
+func TestSystemConfigPathHonorsOverride(t *testing.T) {
+	t.Setenv("GIT_CONFIG_SYSTEM", "/custom/gitconfig")
+	if got := systemConfigPath(); got != "/custom/gitconfig" {
+		t.Fatalf("got %q, want %q", got, "/custom/gitconfig")
+	}
+}
+
+func TestParseConfigStripsTrailingComment(t *testing.T) {
+	src := []byte("[core]\n\tbare = false # do not make this bare\n")
+	cfg := parseConfig(src)
+
+	if got := lookupOption(cfg, "core.bare"); got != "false" {
+		t.Fatalf("got %q, want %q", got, "false")
+	}
+}
+
+func TestParseConfigKeepsCommentCharInsideQuotes(t *testing.T) {
+	src := []byte(`[core]` + "\n\t" + `attr = "a#b" ; trailing comment` + "\n")
+	cfg := parseConfig(src)
+
+	if got := lookupOption(cfg, "core.attr"); got != "a#b" {
+		t.Fatalf("got %q, want %q", got, "a#b")
+	}
+}
+
+func TestParseConfigBareKeyWithTrailingComment(t *testing.T) {
+	src := []byte("[core]\n\tbare # why not\n")
+	cfg := parseConfig(src)
+
+	if got := lookupOption(cfg, "core.bare"); got != "true" {
+		t.Fatalf("got %q, want %q", got, "true")
+	}
+}
+
+func TestFileConfigBackendWorksOutsideARepository(t *testing.T) {
+	dir := t.TempDir()
+	b := &FileConfigBackend{
+		startDir:   dir,
+		globalPath: filepath.Join(t.TempDir(), ".gitconfig"),
+		systemPath: systemConfigPath(),
+	}
+
+	if got := b.FindLocal("core.bare"); got != "" {
+		t.Fatalf("got %q, want empty outside a repository", got)
+	}
+	if _, err := b.SetLocal("core.bare", "true"); err == nil {
+		t.Fatal("expected SetLocal to fail outside a repository")
+	}
+
+	// Global/system scope must still work with no repository present,
+	// e.g. for `git lfs install --global` in an empty container.
+	if got := b.FindGlobal("user.name"); got != "" {
+		t.Fatalf("got %q, want empty for an unset key", got)
+	}
+}
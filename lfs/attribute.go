@@ -36,6 +36,18 @@ type FilterOptions struct {
 	Worktree   bool
 	System     bool
 	SkipSmudge bool
+
+	// ConfigBackend selects which git.ConfigBackend implementation reads
+	// and writes the filter attribute. If empty, it falls back to the
+	// LFS_CONFIG_BACKEND environment variable, and then to the
+	// exec-based backend wrapping GitConfig.
+	ConfigBackend git.ConfigBackendKind
+}
+
+// Backend resolves the git.ConfigBackend that Install and Uninstall should
+// use, per o.ConfigBackend and the LFS_CONFIG_BACKEND environment variable.
+func (o *FilterOptions) Backend() (git.ConfigBackend, error) {
+	return git.NewConfigBackend(git.ResolveConfigBackendKind(o.ConfigBackend), o.GitConfig)
 }
 
 func (o *FilterOptions) Install() error {
@@ -110,6 +122,11 @@ func skipSmudgeFilterAttribute() *Attribute {
 // different value than what is given, and force is false, an error will be
 // returned immediately, and the rest of the attributes will not be set.
 func (a *Attribute) Install(opt *FilterOptions) error {
+	backend, err := opt.Backend()
+	if err != nil {
+		return err
+	}
+
 	for k, v := range a.Properties {
 		var upgradeables []string
 		if a.Upgradeables != nil {
@@ -117,7 +134,7 @@ func (a *Attribute) Install(opt *FilterOptions) error {
 			upgradeables = a.Upgradeables[k]
 		}
 		key := a.normalizeKey(k)
-		if err := a.set(opt.GitConfig, key, v, upgradeables, opt); err != nil {
+		if err := a.set(backend, key, v, upgradeables, opt); err != nil {
 			return err
 		}
 	}
@@ -135,28 +152,28 @@ func (a *Attribute) normalizeKey(relative string) string {
 // matching key already exists and the value is not equal to the desired value,
 // an error will be thrown if force is set to false. If force is true, the value
 // will be overridden.
-func (a *Attribute) set(gitConfig *git.Configuration, key, value string, upgradeables []string, opt *FilterOptions) error {
+func (a *Attribute) set(backend git.ConfigBackend, key, value string, upgradeables []string, opt *FilterOptions) error {
 	var currentValue string
 	if opt.Local {
-		currentValue = gitConfig.FindLocal(key)
+		currentValue = backend.FindLocal(key)
 	} else if opt.Worktree {
-		currentValue = gitConfig.FindWorktree(key)
+		currentValue = backend.FindWorktree(key)
 	} else if opt.System {
-		currentValue = gitConfig.FindSystem(key)
+		currentValue = backend.FindSystem(key)
 	} else {
-		currentValue = gitConfig.FindGlobal(key)
+		currentValue = backend.FindGlobal(key)
 	}
 
 	if opt.Force || shouldReset(currentValue, upgradeables) {
 		var err error
 		if opt.Local {
-			_, err = gitConfig.SetLocal(key, value)
+			_, err = backend.SetLocal(key, value)
 		} else if opt.Worktree {
-			_, err = gitConfig.SetWorktree(key, value)
+			_, err = backend.SetWorktree(key, value)
 		} else if opt.System {
-			_, err = gitConfig.SetSystem(key, value)
+			_, err = backend.SetSystem(key, value)
 		} else {
-			_, err = gitConfig.SetGlobal(key, value)
+			_, err = backend.SetGlobal(key, value)
 		}
 		return err
 	} else if currentValue != value {
@@ -169,15 +186,19 @@ func (a *Attribute) set(gitConfig *git.Configuration, key, value string, upgrade
 
 // Uninstall removes all properties in the path of this property.
 func (a *Attribute) Uninstall(opt *FilterOptions) error {
-	var err error
+	backend, err := opt.Backend()
+	if err != nil {
+		return err
+	}
+
 	if opt.Local {
-		_, err = opt.GitConfig.UnsetLocalSection(a.Section)
+		_, err = backend.UnsetLocalSection(a.Section)
 	} else if opt.Worktree {
-		_, err = opt.GitConfig.UnsetWorktreeSection(a.Section)
+		_, err = backend.UnsetWorktreeSection(a.Section)
 	} else if opt.System {
-		_, err = opt.GitConfig.UnsetSystemSection(a.Section)
+		_, err = backend.UnsetSystemSection(a.Section)
 	} else {
-		_, err = opt.GitConfig.UnsetGlobalSection(a.Section)
+		_, err = backend.UnsetGlobalSection(a.Section)
 	}
 	return err
 }
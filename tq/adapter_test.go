@@ -0,0 +1,67 @@
+package tq
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPAdapterTransferRetriesThroughManifestPolicy(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	m := NewManifest()
+	m.InitAdapters()
+	m.ConfigureRetryPolicy(BasicAdapterName, RetryPolicy{
+		MaxRetries:     5,
+		InitialBackoff: 0,
+		MaxBackoff:     0,
+		Multiplier:     1,
+	})
+
+	adapter := m.NewDownloadAdapter(BasicAdapterName)
+	if adapter == nil {
+		t.Fatal("expected the basic download adapter to be registered")
+	}
+
+	if err := adapter.Transfer("oid", srv.URL); err != nil {
+		t.Fatalf("expected Transfer to succeed after retrying, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestHTTPAdapterTransferGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	m := NewManifest()
+	m.InitAdapters()
+	m.ConfigureRetryPolicy(BasicAdapterName, RetryPolicy{
+		MaxRetries:     2,
+		InitialBackoff: 0,
+		MaxBackoff:     0,
+		Multiplier:     1,
+	})
+
+	adapter := m.NewUploadAdapter(BasicAdapterName)
+	if err := adapter.Transfer("oid", srv.URL); err == nil {
+		t.Fatal("expected Transfer to fail after exhausting retries")
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 1 initial attempt + 2 retries = 3, got %d", attempts)
+	}
+}
@@ -1,6 +1,7 @@
 package tq
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/rubyist/tracerx"
@@ -11,6 +12,32 @@ const (
 	defaultConcurrentTransfers = 3
 )
 
+// RegisterOptions customizes how RegisterNewAdapterFunc registers an
+// adapter constructor.
+type RegisterOptions struct {
+	// Direction is which transfer direction this adapter is being
+	// registered for.
+	Direction Direction
+
+	// Priority controls negotiation order: GetDownloadAdapterNames and
+	// GetUploadAdapterNames return adapters sorted by descending
+	// Priority, with ties broken by registration order. The zero value
+	// is the default priority, which built-in adapters register with;
+	// since they register first, they win ties, and an adapter sorts
+	// ahead of them only with a higher Priority (a negative Priority
+	// sorts after them). BasicAdapterName is always sorted last,
+	// regardless of Priority.
+	Priority int
+}
+
+// adapterRegistration tracks a single adapter constructor along with the
+// ordering information needed to make negotiation deterministic.
+type adapterRegistration struct {
+	fn       NewAdapterFunc
+	priority int
+	order    int
+}
+
 type Manifest struct {
 	// MaxRetries is the maximum number of retries a single object can
 	// attempt to make before it will be dropped.
@@ -19,17 +46,26 @@ type Manifest struct {
 	BasicTransfersOnly  bool `git:"lfs.basictransfersonly"`
 	TusTransfersAllowed bool `git:"lfs.tustransfers"`
 
-	downloadAdapterFuncs map[string]NewAdapterFunc
-	uploadAdapterFuncs   map[string]NewAdapterFunc
+	// RetryPolicy is the default retry behavior for adapters that don't
+	// have a more specific override configured via
+	// lfs.transfer.<adapter>.retry.*. Its MaxRetries field is kept in
+	// sync with the MaxRetries field above.
+	RetryPolicy RetryPolicy
+
+	downloadAdapters     map[string]*adapterRegistration
+	uploadAdapters       map[string]*adapterRegistration
+	registrationSeq      int
+	adapterRetryPolicies map[string]RetryPolicy
 	mu                   sync.Mutex
 }
 
 func NewManifest() *Manifest {
 	return &Manifest{
-		MaxRetries:           defaultMaxRetries,
-		ConcurrentTransfers:  defaultConcurrentTransfers,
-		downloadAdapterFuncs: make(map[string]NewAdapterFunc),
-		uploadAdapterFuncs:   make(map[string]NewAdapterFunc),
+		MaxRetries:          defaultMaxRetries,
+		ConcurrentTransfers: defaultConcurrentTransfers,
+		RetryPolicy:         DefaultRetryPolicy(defaultMaxRetries),
+		downloadAdapters:    make(map[string]*adapterRegistration),
+		uploadAdapters:      make(map[string]*adapterRegistration),
 	}
 }
 
@@ -40,6 +76,9 @@ func (m *Manifest) InitAdapters() {
 	if m.ConcurrentTransfers < 1 {
 		m.ConcurrentTransfers = defaultConcurrentTransfers
 	}
+	// MaxRetries is a backward-compatible shorthand for
+	// RetryPolicy.MaxRetries; keep them in sync.
+	m.RetryPolicy.MaxRetries = m.MaxRetries
 
 	configureBasicDownloadAdapter(m)
 	configureBasicUploadAdapter(m)
@@ -51,6 +90,56 @@ func (m *Manifest) InitAdapters() {
 func (m *Manifest) InitCustomAdaptersFromGit(git env) {
 	m.InitAdapters()
 	configureCustomAdapters(git, m)
+	m.applyAdapterPrioritiesFromGit(git)
+	m.applyRetryPoliciesFromGit(git)
+}
+
+// applyRetryPoliciesFromGit builds a per-adapter RetryPolicy for every
+// adapter that has at least one lfs.transfer.<adapter>.retry.* key set,
+// overriding m.RetryPolicy's fields with whichever keys are present.
+func (m *Manifest) applyRetryPoliciesFromGit(git env) {
+	for adapter := range adapterNamesWithRetryOverrides(git) {
+		m.ConfigureRetryPolicy(adapter, retryPolicyFromGit(git, adapter, m.RetryPolicy))
+	}
+}
+
+// ConfigureRetryPolicy overrides the RetryPolicy used for adapter, in place
+// of m.RetryPolicy.
+func (m *Manifest) ConfigureRetryPolicy(adapter string, policy RetryPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.adapterRetryPolicies == nil {
+		m.adapterRetryPolicies = make(map[string]RetryPolicy)
+	}
+	m.adapterRetryPolicies[adapter] = policy
+}
+
+// RetryPolicyFor returns the RetryPolicy that should govern retries for
+// adapter, falling back to m.RetryPolicy if no override has been
+// configured for it.
+func (m *Manifest) RetryPolicyFor(adapter string) RetryPolicy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.adapterRetryPolicies[adapter]; ok {
+		return p
+	}
+	return m.RetryPolicy
+}
+
+// applyAdapterPrioritiesFromGit reads the lfs.transfer.adapterpriority.<name>
+// git config key for every currently registered adapter, letting users
+// reorder tus, basic, and custom adapters without recompiling.
+func (m *Manifest) applyAdapterPrioritiesFromGit(git env) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, adapters := range [](map[string]*adapterRegistration){m.downloadAdapters, m.uploadAdapters} {
+		for name, reg := range adapters {
+			reg.priority = git.Int("lfs.transfer.adapterpriority."+name, reg.priority)
+		}
+	}
 }
 
 // GetAdapterNames returns a list of the names of adapters available to be created
@@ -64,18 +153,25 @@ func (m *Manifest) GetAdapterNames(dir Direction) []string {
 	return nil
 }
 
-// GetDownloadAdapterNames returns a list of the names of download adapters available to be created
+// GetDownloadAdapterNames returns a list of the names of download adapters
+// available to be created, sorted by descending priority, with ties broken
+// by registration order.
 func (m *Manifest) GetDownloadAdapterNames() []string {
-	return m.getAdapterNames(m.downloadAdapterFuncs)
+	return m.getAdapterNames(m.downloadAdapters)
 }
 
-// GetUploadAdapterNames returns a list of the names of upload adapters available to be created
+// GetUploadAdapterNames returns a list of the names of upload adapters
+// available to be created, sorted by descending priority, with ties broken
+// by registration order.
 func (m *Manifest) GetUploadAdapterNames() []string {
-	return m.getAdapterNames(m.uploadAdapterFuncs)
+	return m.getAdapterNames(m.uploadAdapters)
 }
 
-// getAdapterNames returns a list of the names of adapters available to be created
-func (m *Manifest) getAdapterNames(adapters map[string]NewAdapterFunc) []string {
+// getAdapterNames returns a list of the names of adapters available to be
+// created, deterministically ordered by descending priority (registration
+// order breaking ties), with BasicAdapterName always sorted last so it
+// remains the last-resort adapter no matter its configured priority.
+func (m *Manifest) getAdapterNames(adapters map[string]*adapterRegistration) []string {
 	if m.BasicTransfersOnly {
 		return []string{BasicAdapterName}
 	}
@@ -83,25 +179,56 @@ func (m *Manifest) getAdapterNames(adapters map[string]NewAdapterFunc) []string
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	ret := make([]string, 0, len(adapters))
-	for n, _ := range adapters {
-		ret = append(ret, n)
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
 	}
-	return ret
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == BasicAdapterName {
+			return false
+		}
+		if names[j] == BasicAdapterName {
+			return true
+		}
+
+		ri, rj := adapters[names[i]], adapters[names[j]]
+		if ri.priority != rj.priority {
+			return ri.priority > rj.priority
+		}
+		return ri.order < rj.order
+	})
+
+	return names
 }
 
-// RegisterNewTransferAdapterFunc registers a new function for creating upload
-// or download adapters. If a function with that name & direction is already
-// registered, it is overridden
+// RegisterNewAdapterFunc registers a new function for creating upload or
+// download adapters at the default (zero) priority. If a function with
+// that name & direction is already registered, it is overridden, and its
+// priority is reset to the default.
+//
+// See also RegisterNewAdapterFuncWithOptions, which allows setting a
+// priority to control negotiation order.
 func (m *Manifest) RegisterNewAdapterFunc(name string, dir Direction, f NewAdapterFunc) {
+	m.RegisterNewAdapterFuncWithOptions(name, f, RegisterOptions{Direction: dir})
+}
+
+// RegisterNewAdapterFuncWithOptions registers a new function for creating
+// upload or download adapters, as RegisterNewAdapterFunc does, but also
+// accepts a Priority that controls where the adapter sorts relative to
+// others in GetDownloadAdapterNames/GetUploadAdapterNames.
+func (m *Manifest) RegisterNewAdapterFuncWithOptions(name string, f NewAdapterFunc, opts RegisterOptions) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	switch dir {
+	reg := &adapterRegistration{fn: f, priority: opts.Priority, order: m.registrationSeq}
+	m.registrationSeq++
+
+	switch opts.Direction {
 	case Upload:
-		m.uploadAdapterFuncs[name] = f
+		m.uploadAdapters[name] = reg
 	case Download:
-		m.downloadAdapterFuncs[name] = f
+		m.downloadAdapters[name] = reg
 	}
 }
 
@@ -126,12 +253,12 @@ func (m *Manifest) NewAdapter(name string, dir Direction) Adapter {
 
 	switch dir {
 	case Upload:
-		if u, ok := m.uploadAdapterFuncs[name]; ok {
-			return u(name, dir)
+		if reg, ok := m.uploadAdapters[name]; ok {
+			return reg.fn(name, dir)
 		}
 	case Download:
-		if d, ok := m.downloadAdapterFuncs[name]; ok {
-			return d(name, dir)
+		if reg, ok := m.downloadAdapters[name]; ok {
+			return reg.fn(name, dir)
 		}
 	}
 	return nil
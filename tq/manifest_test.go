@@ -0,0 +1,89 @@
+package tq
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestGetAdapterNamesOrdersByPriorityThenRegistration(t *testing.T) {
+	m := NewManifest()
+
+	m.RegisterNewAdapterFunc(BasicAdapterName, Download, nil)
+	m.RegisterNewAdapterFunc("tus", Download, nil)
+	m.RegisterNewAdapterFunc("custom-a", Download, nil)
+	m.RegisterNewAdapterFuncWithOptions("custom-b", nil, RegisterOptions{Direction: Download, Priority: 5})
+	m.RegisterNewAdapterFuncWithOptions("tus", nil, RegisterOptions{Direction: Download, Priority: 5})
+
+	got := m.GetDownloadAdapterNames()
+	want := []string{"custom-b", "tus", "custom-a", BasicAdapterName}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGetAdapterNamesBasicTransfersOnly(t *testing.T) {
+	m := NewManifest()
+	m.BasicTransfersOnly = true
+
+	m.RegisterNewAdapterFuncWithOptions("tus", nil, RegisterOptions{Direction: Download, Priority: 100})
+
+	got := m.GetDownloadAdapterNames()
+	if !reflect.DeepEqual(got, []string{BasicAdapterName}) {
+		t.Fatalf("got %v, want only %q", got, BasicAdapterName)
+	}
+}
+
+func TestApplyAdapterPrioritiesFromGit(t *testing.T) {
+	m := NewManifest()
+	m.RegisterNewAdapterFunc("custom", Download, nil)
+	m.RegisterNewAdapterFunc(BasicAdapterName, Download, nil)
+
+	git := newFakeEnv(map[string]string{
+		"lfs.transfer.adapterpriority.custom": "10",
+	})
+
+	m.applyAdapterPrioritiesFromGit(git)
+
+	got := m.GetDownloadAdapterNames()
+	want := []string{"custom", BasicAdapterName}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+// fakeEnv is a minimal env backed by a plain map, for tests that need to
+// drive Manifest's git-config-reading code without a real git repository.
+type fakeEnv struct {
+	values map[string]string
+}
+
+func newFakeEnv(values map[string]string) *fakeEnv {
+	return &fakeEnv{values: values}
+}
+
+func (e *fakeEnv) Get(key string) (string, bool) {
+	v, ok := e.values[key]
+	return v, ok
+}
+
+func (e *fakeEnv) Bool(key string, def bool) bool {
+	if v, ok := e.values[key]; ok {
+		return v == "true"
+	}
+	return def
+}
+
+func (e *fakeEnv) Int(key string, def int) int {
+	if v, ok := e.values[key]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+func (e *fakeEnv) All() map[string]string {
+	return e.values
+}
@@ -0,0 +1,227 @@
+package tq
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInitialBackoff = 1 * time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2.0
+	defaultJitterFraction = 0.2
+)
+
+// RetryPolicy controls how a transfer adapter retries a failed transfer:
+// how many times, how long to wait between attempts, and how many retries
+// are allowed across all concurrent transfers in a given window, so a
+// struggling LFS server isn't hit with a retry storm. A Manifest has one
+// RetryPolicy by default, and may have per-adapter overrides configured via
+// lfs.transfer.<adapter>.retry.* git config keys; see RetryPolicyFor.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries a single object can
+	// attempt before it is dropped. Kept in sync with Manifest.MaxRetries
+	// as a backward-compatible shorthand.
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry. A zero or
+	// negative value uses the default of 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed delay between retries. A zero or
+	// negative value uses the default of 30s.
+	MaxBackoff time.Duration
+	// Multiplier scales the backoff after each failed attempt. A zero or
+	// negative value uses the default of 2.
+	Multiplier float64
+	// JitterFraction randomizes the computed backoff by up to this
+	// fraction in either direction, so concurrent clients don't retry in
+	// lockstep.
+	JitterFraction float64
+
+	// RetryBudget limits the total number of retries granted per unit
+	// time across all concurrent transfers sharing this policy. A nil
+	// RetryBudget does not limit retries.
+	RetryBudget *RetryBudget
+}
+
+// DefaultRetryPolicy returns the RetryPolicy used when no per-adapter
+// override is configured, with MaxRetries set as given.
+func DefaultRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     maxRetries,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Multiplier:     defaultMultiplier,
+		JitterFraction: defaultJitterFraction,
+	}
+}
+
+// RetryBudgetExhaustedError is returned by RetryPolicy.NextBackoff when the
+// policy's RetryBudget has no tokens available; the object should be
+// dropped rather than retried.
+type RetryBudgetExhaustedError struct {
+	Adapter string
+}
+
+func (e *RetryBudgetExhaustedError) Error() string {
+	return fmt.Sprintf("tq: retry budget exhausted for %q adapter", e.Adapter)
+}
+
+// NextBackoff computes how long to wait before retrying attempt (0-based)
+// against adapter, and consults the policy's RetryBudget. retryAfter is the
+// value of a Retry-After response header, if the server sent one (zero
+// otherwise); when present, it clamps the computed sleep up to at least
+// that value. It returns a *RetryBudgetExhaustedError if the budget has no
+// token available, in which case the caller should not retry.
+func (p *RetryPolicy) NextBackoff(adapter string, attempt int, retryAfter time.Duration, now time.Time) (time.Duration, error) {
+	if p.RetryBudget != nil && !p.RetryBudget.Take(now) {
+		return 0, &RetryBudgetExhaustedError{Adapter: adapter}
+	}
+
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = defaultMultiplier
+	}
+
+	sleep := time.Duration(float64(initial) * math.Pow(mult, float64(attempt)))
+	if sleep > maxBackoff {
+		sleep = maxBackoff
+	}
+
+	if p.JitterFraction > 0 {
+		jitter := (rand.Float64()*2 - 1) * p.JitterFraction
+		sleep = time.Duration(float64(sleep) * (1 + jitter))
+	}
+
+	if retryAfter > sleep {
+		sleep = retryAfter
+	}
+
+	return sleep, nil
+}
+
+// RetryAfterFromHeader parses the delay-seconds form of a Retry-After
+// response header (RFC 7231 section 7.1.3; the HTTP-date form is not
+// supported), returning zero if the header is absent or malformed.
+func RetryAfterFromHeader(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// RetryBudget is a token bucket limiting how many retries may be granted
+// per unit time, shared across all concurrent transfers using the same
+// RetryPolicy. This keeps a flood of simultaneously-failing transfers from
+// hammering a struggling LFS server with retries.
+type RetryBudget struct {
+	mu         sync.Mutex
+	tokens     float64
+	max        float64
+	refillRate float64 // tokens added per second
+	last       time.Time
+}
+
+// NewRetryBudget creates a RetryBudget holding up to max tokens, refilling
+// at refillPerSecond tokens per second, starting from now.
+func NewRetryBudget(max float64, refillPerSecond float64, now time.Time) *RetryBudget {
+	return &RetryBudget{
+		tokens:     max,
+		max:        max,
+		refillRate: refillPerSecond,
+		last:       now,
+	}
+}
+
+// Take attempts to withdraw a single retry token as of now, returning
+// whether one was available.
+func (b *RetryBudget) Take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.max, b.tokens+elapsed*b.refillRate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryConfigPrefix is the git config namespace under which per-adapter
+// retry overrides live: lfs.transfer.<adapter>.retry.<field>.
+const retryConfigPrefix = "lfs.transfer."
+
+// adapterNamesWithRetryOverrides scans every git config key for ones
+// shaped like lfs.transfer.<adapter>.retry.<field> and returns the set of
+// <adapter> names found, so callers don't need to know in advance which
+// adapters have been given an override.
+func adapterNamesWithRetryOverrides(git env) map[string]struct{} {
+	names := make(map[string]struct{})
+	for key := range git.All() {
+		rest := strings.TrimPrefix(key, retryConfigPrefix)
+		if rest == key {
+			continue
+		}
+		idx := strings.Index(rest, ".retry.")
+		if idx < 0 {
+			continue
+		}
+		names[rest[:idx]] = struct{}{}
+	}
+	return names
+}
+
+// retryPolicyFromGit builds the RetryPolicy for adapter by overriding base
+// with whichever lfs.transfer.<adapter>.retry.* keys are present in git;
+// any field without a corresponding key keeps base's value.
+func retryPolicyFromGit(git env, adapter string, base RetryPolicy) RetryPolicy {
+	policy := base
+	prefix := retryConfigPrefix + adapter + ".retry."
+
+	policy.MaxRetries = git.Int(prefix+"maxretries", policy.MaxRetries)
+
+	if secs := git.Int(prefix+"initialbackoff", -1); secs >= 0 {
+		policy.InitialBackoff = time.Duration(secs) * time.Second
+	}
+	if secs := git.Int(prefix+"maxbackoff", -1); secs >= 0 {
+		policy.MaxBackoff = time.Duration(secs) * time.Second
+	}
+	if v, ok := git.Get(prefix + "multiplier"); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			policy.Multiplier = f
+		}
+	}
+	if v, ok := git.Get(prefix + "jitterfraction"); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			policy.JitterFraction = f
+		}
+	}
+	if budget := git.Int(prefix+"budget", -1); budget >= 0 {
+		policy.RetryBudget = NewRetryBudget(float64(budget), float64(budget), time.Now())
+	}
+
+	return policy
+}
@@ -0,0 +1,51 @@
+package tq
+
+import (
+	"net/http"
+	"time"
+)
+
+// Attempt performs a single try at transferring one object against one
+// adapter. It returns the response headers it received, if any (so
+// RunWithRetries can honor a Retry-After header on failure), and an error
+// if the attempt did not succeed.
+type Attempt func() (headers http.Header, err error)
+
+// RunWithRetries drives do to completion, retrying it according to m's
+// RetryPolicy for adapter (see Manifest.RetryPolicyFor): on failure it
+// computes the next backoff with RetryPolicy.NextBackoff, sleeps for that
+// long, and tries again, up to the policy's MaxRetries. Once MaxRetries is
+// exhausted, the last attempt's error is returned to the caller. If the
+// policy's RetryBudget has no token available, the object is dropped and
+// a *RetryBudgetExhaustedError is returned instead of being retried further.
+//
+// This is the integration point transfer adapters call from their transfer
+// loop in place of comparing attempt counts against Manifest.MaxRetries
+// directly, so that per-adapter backoff, jitter, and the retry budget are
+// actually applied rather than just being configured.
+func (m *Manifest) RunWithRetries(adapter string, do Attempt) error {
+	policy := m.RetryPolicyFor(adapter)
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		headers, err := do()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if attempt >= policy.MaxRetries {
+			return lastErr
+		}
+
+		sleep, err := policy.NextBackoff(adapter, attempt, RetryAfterFromHeader(headers), time.Now())
+		if err != nil {
+			// The retry budget is exhausted: drop the object with
+			// that distinct error rather than lastErr, so callers
+			// can tell a budget cutoff apart from the transfer's
+			// own failure.
+			return err
+		}
+		time.Sleep(sleep)
+	}
+}
@@ -0,0 +1,142 @@
+package tq
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryBudgetTakeRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewRetryBudget(2, 1, now) // 2 tokens, refilling at 1/sec
+
+	if !b.Take(now) {
+		t.Fatal("expected a token to be available")
+	}
+	if !b.Take(now) {
+		t.Fatal("expected a second token to be available")
+	}
+	if b.Take(now) {
+		t.Fatal("expected the bucket to be empty")
+	}
+
+	// No time has passed, so still empty.
+	if b.Take(now) {
+		t.Fatal("expected the bucket to still be empty with no elapsed time")
+	}
+
+	later := now.Add(1500 * time.Millisecond)
+	if !b.Take(later) {
+		t.Fatal("expected a refilled token after 1.5s at 1 token/sec")
+	}
+	if b.Take(later) {
+		t.Fatal("expected only one token to have refilled")
+	}
+}
+
+func TestRetryPolicyNextBackoffBudgetExhausted(t *testing.T) {
+	now := time.Unix(0, 0)
+	policy := DefaultRetryPolicy(3)
+	policy.RetryBudget = NewRetryBudget(1, 0, now)
+
+	if _, err := policy.NextBackoff("basic", 0, 0, now); err != nil {
+		t.Fatalf("expected the first retry to be granted a token, got %v", err)
+	}
+
+	_, err := policy.NextBackoff("basic", 1, 0, now)
+	if err == nil {
+		t.Fatal("expected the second retry to exhaust the budget")
+	}
+	if _, ok := err.(*RetryBudgetExhaustedError); !ok {
+		t.Fatalf("expected a *RetryBudgetExhaustedError, got %T", err)
+	}
+}
+
+func TestRetryPolicyNextBackoffHonorsRetryAfter(t *testing.T) {
+	now := time.Unix(0, 0)
+	policy := DefaultRetryPolicy(3)
+	policy.JitterFraction = 0
+
+	sleep, err := policy.NextBackoff("basic", 0, 10*time.Second, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sleep != 10*time.Second {
+		t.Fatalf("expected Retry-After to clamp sleep up to 10s, got %s", sleep)
+	}
+}
+
+func TestRetryAfterFromHeader(t *testing.T) {
+	h := http.Header{}
+	if d := RetryAfterFromHeader(h); d != 0 {
+		t.Fatalf("expected 0 for missing header, got %s", d)
+	}
+
+	h.Set("Retry-After", "5")
+	if d := RetryAfterFromHeader(h); d != 5*time.Second {
+		t.Fatalf("expected 5s, got %s", d)
+	}
+
+	h.Set("Retry-After", "not-a-number")
+	if d := RetryAfterFromHeader(h); d != 0 {
+		t.Fatalf("expected 0 for malformed header, got %s", d)
+	}
+}
+
+func TestRunWithRetriesDropsOnBudgetExhausted(t *testing.T) {
+	m := NewManifest()
+	m.InitAdapters()
+	m.ConfigureRetryPolicy("basic", RetryPolicy{
+		MaxRetries:     5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+		RetryBudget:    NewRetryBudget(0, 0, time.Unix(0, 0)),
+	})
+
+	attempts := 0
+	err := m.RunWithRetries("basic", func() (http.Header, error) {
+		attempts++
+		return nil, errBoom
+	})
+
+	if attempts != 1 {
+		t.Fatalf("expected exactly one attempt before the budget cut it off, got %d", attempts)
+	}
+	if _, ok := err.(*RetryBudgetExhaustedError); !ok {
+		t.Fatalf("expected a *RetryBudgetExhaustedError, got %v", err)
+	}
+}
+
+func TestRunWithRetriesSucceedsEventually(t *testing.T) {
+	m := NewManifest()
+	m.InitAdapters()
+	m.ConfigureRetryPolicy("basic", RetryPolicy{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Multiplier:     2,
+	})
+
+	attempts := 0
+	err := m.RunWithRetries("basic", func() (http.Header, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errBoom
+		}
+		return nil, nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected eventual success, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+type boomError struct{}
+
+func (boomError) Error() string { return "boom" }
+
+var errBoom = boomError{}